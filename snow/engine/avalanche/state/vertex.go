@@ -0,0 +1,265 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package state
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/consensus/snowstorm/conflicts"
+)
+
+// maxVerifyWorkers bounds how many goroutines innerVertex.Verify fans its
+// per-tx work out to. It's capped at NumCPU so vertices with only a handful
+// of txs don't oversubscribe the scheduler, and capped overall so a vertex
+// with thousands of txs doesn't spin up thousands of goroutines.
+const maxVerifyWorkers = 16
+
+var (
+	errInvalidParents = errors.New("vertex contains non-sorted or duplicated parentIDs")
+	errNoTxs          = errors.New("vertex contains no transactions")
+	errInvalidTxs     = errors.New("vertex contains non-sorted or duplicated transactions")
+	errConflictingTxs = errors.New("vertex contains conflicting transactions")
+)
+
+// concurrentlyVerifiable is implemented by conflicts.Tx implementations that
+// have been audited as safe to have Verify and InputIDs called concurrently
+// with other txs from the same vertex, e.g. because they only touch their
+// own fields and a shared, read-only state snapshot. Nothing in this tree
+// audits every conflicts.Tx implementation for that property, so
+// verifyTxsParallel's fan-out is only used when every tx in a vertex opts
+// in by implementing this interface; verifyTxsSerial is the safe fallback
+// for everything else.
+type concurrentlyVerifiable interface {
+	// ConcurrentlyVerifiable is a marker method; its return value is never
+	// used.
+	ConcurrentlyVerifiable()
+}
+
+// innerVertex holds the raw contents of a vertex: its parents and the txs it
+// proposes to add to consensus.
+type innerVertex struct {
+	id        ids.ID
+	chainID   ids.ID
+	height    uint64
+	parentIDs []ids.ID
+	txs       []conflicts.Tx
+}
+
+// Verify that this vertex is well-formed: its parents and txs are each
+// sorted and duplicate-free, it contains at least one tx, and no two of its
+// txs spend the same input.
+//
+// The sort/uniqueness checks run serially up front since they're cheap and
+// any of them failing makes the (expensive) conflict check moot. The
+// conflict check itself takes one of two paths, chosen by verifyTxs: if
+// every tx implements concurrentlyVerifiable, a two-phase parallel pipeline
+// is used -- phase 1 fans the per-tx InputIDs()/Verify() work out across a
+// bounded worker pool, each worker accumulating its own ids.Set; phase 2
+// merges those sets pairwise, divide-and-conquer style, aborting as soon as
+// a collision is found. For vertices with hundreds of txs (common on the
+// X-chain) this keeps verification latency close to the slowest single
+// worker's share of the work instead of the sum of every tx's cost.
+// Otherwise, verifyTxsSerial walks the txs one at a time, which is always
+// safe regardless of what a tx's Verify/InputIDs touch.
+func (vtx *innerVertex) Verify() error {
+	if err := verifySortedAndUniqueIDs(vtx.parentIDs); err != nil {
+		return errInvalidParents
+	}
+	if len(vtx.txs) == 0 {
+		return errNoTxs
+	}
+	if err := verifySortedAndUniqueTxs(vtx.txs); err != nil {
+		return errInvalidTxs
+	}
+
+	_, err := verifyTxs(vtx.txs)
+	return err
+}
+
+// verifyTxs picks between the parallel and serial conflict-check paths:
+// verifyTxsParallel is only used when every tx in [txs] implements
+// concurrentlyVerifiable, since that's the only case this package has any
+// basis to assume concurrent Verify/InputIDs calls are safe.
+func verifyTxs(txs []conflicts.Tx) (ids.Set, error) {
+	if allConcurrentlyVerifiable(txs) {
+		return verifyTxsParallel(txs)
+	}
+	return verifyTxsSerial(txs)
+}
+
+// allConcurrentlyVerifiable reports whether every tx in [txs] implements
+// concurrentlyVerifiable.
+func allConcurrentlyVerifiable(txs []conflicts.Tx) bool {
+	for _, tx := range txs {
+		if _, ok := tx.(concurrentlyVerifiable); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// verifyTxsSerial calls Verify and collects InputIDs for every tx in [txs],
+// one at a time. It's the fallback verifyTxs uses for txs that haven't
+// opted into concurrentlyVerifiable, so it makes no assumption about
+// whether a tx's Verify/InputIDs methods are safe to call alongside other
+// txs.
+func verifyTxsSerial(txs []conflicts.Tx) (ids.Set, error) {
+	inputs := ids.Set{}
+	for _, tx := range txs {
+		if err := tx.Verify(); err != nil {
+			return ids.Set{}, fmt.Errorf("tx %s failed verification: %w", tx.ID(), err)
+		}
+		for inputID := range tx.InputIDs() {
+			if inputs.Contains(inputID) {
+				return ids.Set{}, errConflictingTxs
+			}
+			inputs.Add(inputID)
+		}
+	}
+	return inputs, nil
+}
+
+// verifySortedAndUniqueIDs returns an error if [idList] isn't strictly
+// ascending.
+func verifySortedAndUniqueIDs(idList []ids.ID) error {
+	for i := 1; i < len(idList); i++ {
+		prev, cur := idList[i-1], idList[i]
+		if bytes.Compare(prev[:], cur[:]) >= 0 {
+			return errInvalidParents
+		}
+	}
+	return nil
+}
+
+// verifySortedAndUniqueTxs returns an error if [txs] isn't strictly
+// ascending by tx ID.
+func verifySortedAndUniqueTxs(txs []conflicts.Tx) error {
+	for i := 1; i < len(txs); i++ {
+		prevID, curID := txs[i-1].ID(), txs[i].ID()
+		if bytes.Compare(prevID[:], curID[:]) >= 0 {
+			return errInvalidTxs
+		}
+	}
+	return nil
+}
+
+// sortTxs sorts [txs] ascending by tx ID, the order Verify requires.
+func sortTxs(txs []conflicts.Tx) {
+	sort.Sort(txsByID(txs))
+}
+
+type txsByID []conflicts.Tx
+
+func (s txsByID) Len() int      { return len(s) }
+func (s txsByID) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s txsByID) Less(i, j int) bool {
+	iID, jID := s[i].ID(), s[j].ID()
+	return bytes.Compare(iID[:], jID[:]) < 0
+}
+
+// verifyTxsParallel calls Verify and collects InputIDs for every tx in
+// [txs], split across a bounded worker pool, then merges the per-worker
+// input sets. Callers must only reach this for txs that all implement
+// concurrentlyVerifiable -- verifyTxs is what enforces that -- since this
+// function itself has no way to confirm a given tx's Verify/InputIDs are
+// actually safe to call concurrently with other txs. It returns the merged
+// set of every tx's inputs, or errConflictingTxs the first time it finds
+// the same input claimed twice, whether that collision is within a single
+// worker's share of the work or across two workers' shares.
+func verifyTxsParallel(txs []conflicts.Tx) (ids.Set, error) {
+	numWorkers := maxVerifyWorkers
+	if cpus := runtime.NumCPU(); cpus < numWorkers {
+		numWorkers = cpus
+	}
+	if numWorkers > len(txs) {
+		numWorkers = len(txs)
+	}
+
+	workerSets := make([]ids.Set, numWorkers)
+	workerErrs := make([]error, numWorkers)
+
+	var wg sync.WaitGroup
+	wg.Add(numWorkers)
+	for w := 0; w < numWorkers; w++ {
+		w := w
+		go func() {
+			defer wg.Done()
+
+			inputs := ids.Set{}
+			for i := w; i < len(txs); i += numWorkers {
+				tx := txs[i]
+				if err := tx.Verify(); err != nil {
+					workerErrs[w] = fmt.Errorf("tx %s failed verification: %w", tx.ID(), err)
+					return
+				}
+				for inputID := range tx.InputIDs() {
+					if inputs.Contains(inputID) {
+						workerErrs[w] = errConflictingTxs
+						return
+					}
+					inputs.Add(inputID)
+				}
+			}
+			workerSets[w] = inputs
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range workerErrs {
+		if err != nil {
+			return ids.Set{}, err
+		}
+	}
+	return mergeInputSets(workerSets)
+}
+
+// mergeInputSets unions [sets] pairwise using divide-and-conquer, returning
+// errConflictingTxs as soon as two halves are found to share an input.
+func mergeInputSets(sets []ids.Set) (ids.Set, error) {
+	switch len(sets) {
+	case 0:
+		return ids.Set{}, nil
+	case 1:
+		return sets[0], nil
+	}
+
+	mid := len(sets) / 2
+
+	var (
+		left, right       ids.Set
+		leftErr, rightErr error
+		wg                sync.WaitGroup
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		left, leftErr = mergeInputSets(sets[:mid])
+	}()
+	go func() {
+		defer wg.Done()
+		right, rightErr = mergeInputSets(sets[mid:])
+	}()
+	wg.Wait()
+
+	if leftErr != nil {
+		return ids.Set{}, leftErr
+	}
+	if rightErr != nil {
+		return ids.Set{}, rightErr
+	}
+
+	for inputID := range right {
+		if left.Contains(inputID) {
+			return ids.Set{}, errConflictingTxs
+		}
+	}
+	left.Union(right)
+	return left, nil
+}
@@ -131,3 +131,143 @@ func TestVertexVerify(t *testing.T) {
 		t.Fatal("Vertex with conflicting transactions should not have passed verification")
 	}
 }
+
+// concurrentTestTx wraps a *conflicts.TestTx with a ConcurrentlyVerifiable
+// marker method, so tests can opt fixtures into verifyTxsParallel's fan-out
+// path instead of exercising only the always-safe serial fallback.
+type concurrentTestTx struct {
+	*conflicts.TestTx
+}
+
+func (*concurrentTestTx) ConcurrentlyVerifiable() {}
+
+// manyTxs builds [n] non-conflicting txs, each with its own unique input,
+// suitable for exercising the parallel verification pipeline across
+// multiple workers.
+func manyTxs(n int) []conflicts.Tx {
+	txs := make([]conflicts.Tx, n)
+	for i := 0; i < n; i++ {
+		inputs := ids.Set{}
+		inputs.Add(ids.NewID([32]byte{byte(i), byte(i >> 8), 'i', 'n'}))
+		txs[i] = &concurrentTestTx{TestTx: &conflicts.TestTx{
+			TestDecidable: choices.TestDecidable{
+				IDV: ids.NewID([32]byte{byte(i), byte(i >> 8), 't', 'x'}),
+			},
+			InputIDsV: inputs,
+		}}
+	}
+	sortTxs(txs)
+	return txs
+}
+
+func TestVertexVerifyManyTxs(t *testing.T) {
+	vtx := &innerVertex{
+		id:        ids.NewID([32]byte{}),
+		chainID:   ids.NewID([32]byte{1}),
+		height:    1,
+		parentIDs: []ids.ID{ids.NewID([32]byte{2})},
+		txs:       manyTxs(500),
+	}
+
+	if err := vtx.Verify(); err != nil {
+		t.Fatalf("vertex with many non-conflicting txs failed verification: %s", err)
+	}
+}
+
+// TestVertexVerifyManyTxsEarlyAbort makes two txs, far enough apart in the
+// list that a bounded worker pool will place them in different workers'
+// shares, claim the same input. With at least two workers (NumCPU >= 2) the
+// conflict can only be caught during the phase-2 merge rather than within a
+// single worker's phase-1 pass, so this exercises the merge pipeline's
+// early-abort path; with a single worker it is caught in phase 1 instead.
+func TestVertexVerifyManyTxsEarlyAbort(t *testing.T) {
+	txs := manyTxs(500)
+
+	sharedInput := ids.NewID([32]byte{'s', 'h', 'a', 'r', 'e', 'd'})
+	txs[0].(*concurrentTestTx).InputIDsV.Add(sharedInput)
+	txs[len(txs)-1].(*concurrentTestTx).InputIDsV.Add(sharedInput)
+
+	vtx := &innerVertex{
+		id:        ids.NewID([32]byte{}),
+		chainID:   ids.NewID([32]byte{1}),
+		height:    1,
+		parentIDs: []ids.ID{ids.NewID([32]byte{2})},
+		txs:       txs,
+	}
+
+	if err := vtx.Verify(); err == nil {
+		t.Fatal("vertex with a conflict spanning two workers should not have passed verification")
+	}
+}
+
+// TestVerifyTxsFallsBackToSerialForUnmarkedTxs checks that a tx which
+// doesn't implement concurrentlyVerifiable forces verifyTxs down the serial
+// path -- plain *conflicts.TestTx fixtures (manyTxs wraps them in
+// concurrentTestTx precisely so the parallel-path tests above don't go
+// through this fallback) -- and that conflict detection still works there.
+func TestVerifyTxsFallsBackToSerialForUnmarkedTxs(t *testing.T) {
+	inputs := ids.Set{}
+	inputs.Add(ids.NewID([32]byte{'i', 'n'}))
+	tx0 := &conflicts.TestTx{
+		TestDecidable: choices.TestDecidable{IDV: ids.NewID([32]byte{'t', 'x', '0'})},
+		InputIDsV:     inputs,
+	}
+	txs := []conflicts.Tx{tx0}
+
+	if allConcurrentlyVerifiable(txs) {
+		t.Fatal("a plain conflicts.TestTx should not be considered concurrently verifiable")
+	}
+
+	merged, err := verifyTxs(txs)
+	if err != nil {
+		t.Fatalf("unexpected error from the serial fallback: %s", err)
+	}
+	if !merged.Contains(ids.NewID([32]byte{'i', 'n'})) {
+		t.Fatal("expected the serial fallback to still collect tx0's inputs")
+	}
+
+	conflictingTx := &conflicts.TestTx{
+		TestDecidable: choices.TestDecidable{IDV: ids.NewID([32]byte{'t', 'x', '1'})},
+		InputIDsV:     inputs,
+	}
+	if _, err := verifyTxs([]conflicts.Tx{tx0, conflictingTx}); err != errConflictingTxs {
+		t.Fatalf("expected the serial fallback to catch the conflict, got %v", err)
+	}
+}
+
+// TestAllConcurrentlyVerifiableRequiresEveryTx checks that a mix of
+// marker-implementing and plain txs is never treated as safe to
+// parallelize: one unmarked tx is enough to force the whole vertex down the
+// serial path.
+func TestAllConcurrentlyVerifiableRequiresEveryTx(t *testing.T) {
+	marked := &concurrentTestTx{TestTx: &conflicts.TestTx{
+		TestDecidable: choices.TestDecidable{IDV: ids.NewID([32]byte{'m'})},
+	}}
+	unmarked := &conflicts.TestTx{
+		TestDecidable: choices.TestDecidable{IDV: ids.NewID([32]byte{'u'})},
+	}
+
+	if !allConcurrentlyVerifiable([]conflicts.Tx{marked}) {
+		t.Fatal("a tx implementing concurrentlyVerifiable should be reported as such")
+	}
+	if allConcurrentlyVerifiable([]conflicts.Tx{marked, unmarked}) {
+		t.Fatal("mixing in one unmarked tx should make the whole set unsafe to parallelize")
+	}
+}
+
+func BenchmarkVertexVerify(b *testing.B) {
+	vtx := &innerVertex{
+		id:        ids.NewID([32]byte{}),
+		chainID:   ids.NewID([32]byte{1}),
+		height:    1,
+		parentIDs: []ids.ID{ids.NewID([32]byte{2})},
+		txs:       manyTxs(500),
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := vtx.Verify(); err != nil {
+			b.Fatalf("unexpected verification failure: %s", err)
+		}
+	}
+}
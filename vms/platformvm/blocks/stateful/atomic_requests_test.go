@@ -0,0 +1,116 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package stateful
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/chains/atomic"
+	"github.com/ava-labs/avalanchego/database"
+	"github.com/ava-labs/avalanchego/ids"
+)
+
+// fakeCommitBatcher simulates the VM's database for applyAtomicRequests
+// tests. Setting err simulates a crash in CommitBatch, before shared memory
+// is ever touched.
+type fakeCommitBatcher struct {
+	err     error
+	commits int
+}
+
+func (db *fakeCommitBatcher) CommitBatch() (database.Batch, error) {
+	if db.err != nil {
+		return nil, db.err
+	}
+	db.commits++
+	return nil, nil
+}
+
+// fakeSharedMemory simulates atomic.SharedMemory. Setting applyErr
+// simulates a crash while applying the VM's batch and the atomic requests
+// to shared memory, the call that writes both together.
+type fakeSharedMemory struct {
+	applyErr error
+	applied  map[ids.ID]*atomic.Requests
+}
+
+func (sm *fakeSharedMemory) Apply(requests map[ids.ID]*atomic.Requests, _ ...database.Batch) error {
+	if sm.applyErr != nil {
+		return sm.applyErr
+	}
+	sm.applied = requests
+	return nil
+}
+
+func TestApplyAtomicRequestsNoOpWithoutRequests(t *testing.T) {
+	db := &fakeCommitBatcher{}
+	sm := &fakeSharedMemory{}
+
+	if err := applyAtomicRequests(db, sm, nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if db.commits != 0 {
+		t.Fatal("should not commit a batch when there are no atomic requests")
+	}
+	if sm.applied != nil {
+		t.Fatal("should not touch shared memory when there are no atomic requests")
+	}
+}
+
+// TestApplyAtomicRequestsCrashBeforeCommit simulates a crash in CommitBatch,
+// before the VM's batch is handed to shared memory. Shared memory must never
+// be touched in that case, or the P-chain and X-chain would desync in the
+// other direction (reward visible on X-chain without ever landing locally).
+func TestApplyAtomicRequestsCrashBeforeCommit(t *testing.T) {
+	db := &fakeCommitBatcher{err: errors.New("simulated crash committing VM's database")}
+	sm := &fakeSharedMemory{}
+	requests := map[ids.ID]*atomic.Requests{ids.GenerateTestID(): {}}
+
+	if err := applyAtomicRequests(db, sm, requests); err == nil {
+		t.Fatal("expected an error when the database commit fails")
+	}
+	if sm.applied != nil {
+		t.Fatal("shared memory should never be touched if the local batch never committed")
+	}
+}
+
+// TestApplyAtomicRequestsCrashApplyingSharedMemory simulates a crash in the
+// shared-memory apply, where the VM's batch and the atomic requests are
+// written together. The caller must see an
+// error so it knows this block did not fully accept, rather than silently
+// treating the reward as delivered.
+func TestApplyAtomicRequestsCrashApplyingSharedMemory(t *testing.T) {
+	db := &fakeCommitBatcher{}
+	sm := &fakeSharedMemory{applyErr: errors.New("simulated crash applying to shared memory")}
+	requests := map[ids.ID]*atomic.Requests{ids.GenerateTestID(): {}}
+
+	if err := applyAtomicRequests(db, sm, requests); err == nil {
+		t.Fatal("expected an error when the shared memory apply fails")
+	}
+	if db.commits != 1 {
+		t.Fatalf("expected exactly one batch commit, got %d", db.commits)
+	}
+}
+
+func TestApplyAtomicRequestsSuccess(t *testing.T) {
+	db := &fakeCommitBatcher{}
+	sm := &fakeSharedMemory{}
+	chainID := ids.GenerateTestID()
+	requests := map[ids.ID]*atomic.Requests{
+		chainID: {
+			PutRequests: []*atomic.Element{{Key: []byte("reward")}},
+		},
+	}
+
+	if err := applyAtomicRequests(db, sm, requests); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if db.commits != 1 {
+		t.Fatalf("expected exactly one batch commit, got %d", db.commits)
+	}
+	if len(sm.applied) != 1 || sm.applied[chainID] == nil {
+		t.Fatal("expected the atomic requests to be applied to shared memory")
+	}
+}
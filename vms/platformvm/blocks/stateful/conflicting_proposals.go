@@ -0,0 +1,64 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package stateful
+
+import (
+	"fmt"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs/executor"
+)
+
+// BuildConflictingProposals builds several sibling ProposalBlocks that all
+// share [parentID] as their parent but carry mutually-exclusive proposals,
+// e.g. two AdvanceTimeTx with different timestamps, or two AddValidatorTx
+// that reuse the same signer UTXOs. Each sibling is built off of [parentID]
+// and verified independently through the Manager's verifyProposalBlock; no
+// state.Diff is built for it here. Callers can then drive the whole set
+// through Verify/Accept/Reject.
+//
+// It returns the built siblings alongside the set of their tx IDs, which is
+// the conflict set. It does not check that the txs actually conflict, and
+// with the Manager returned by NewManager, accepting one sibling does not
+// make another sibling's Verify or Accept fail.
+func (m *manager) BuildConflictingProposals(
+	txExecutorBackend executor.Backend,
+	parentID ids.ID,
+	height uint64,
+	conflictingTxs []*txs.Tx,
+) ([]*ProposalBlock, ids.Set, error) {
+	return buildConflictingProposals(m, txExecutorBackend, parentID, height, conflictingTxs)
+}
+
+// buildConflictingProposals is shared by every Manager implementation's
+// BuildConflictingProposals: it always constructs siblings against [self] so
+// that embedding fakes (which override individual Manager methods but can't
+// override methods promoted from the embedded manager) still see their own
+// overrides used for Verify/Accept/Reject on the siblings it builds.
+func buildConflictingProposals(
+	self Manager,
+	txExecutorBackend executor.Backend,
+	parentID ids.ID,
+	height uint64,
+	conflictingTxs []*txs.Tx,
+) ([]*ProposalBlock, ids.Set, error) {
+	siblings := make([]*ProposalBlock, 0, len(conflictingTxs))
+	conflicts := ids.Set{}
+
+	for _, tx := range conflictingTxs {
+		pb, err := NewProposalBlock(self, txExecutorBackend, parentID, height, tx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to build sibling proposal for %s: %w", tx.ID(), err)
+		}
+		if err := pb.Verify(); err != nil {
+			return nil, nil, fmt.Errorf("sibling proposal %s failed verification: %w", pb.ID(), err)
+		}
+
+		siblings = append(siblings, pb)
+		conflicts.Add(tx.ID())
+	}
+
+	return siblings, conflicts, nil
+}
@@ -0,0 +1,266 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package stateful
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ava-labs/avalanchego/chains/atomic"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs/executor"
+)
+
+// Manager tracks the state of the P-chain's block DAG as stateful blocks are
+// verified, accepted and rejected. Every stateful block type embeds a
+// Manager so that blocks share one view of chain state instead of each
+// keeping its own.
+type Manager interface {
+	verifyProposalBlock(b *ProposalBlock) error
+	acceptProposalBlock(b *ProposalBlock) error
+	rejectProposalBlock(b *ProposalBlock) error
+	conflictsProposalBlock(b *ProposalBlock, s ids.Set) (bool, error)
+	freeProposalBlock(b *ProposalBlock)
+	setBaseStateProposalBlock(b *ProposalBlock)
+
+	// registerProposalAtomicRequests records [requests] as the
+	// shared-memory side effects [proposalBlockID]'s proposal would have,
+	// so whichever of its Commit/Abort children actually gets accepted can
+	// later decide whether to apply or discard them. It is a no-op for an
+	// empty or nil [requests].
+	registerProposalAtomicRequests(proposalBlockID ids.ID, requests map[ids.ID]*atomic.Requests)
+
+	// acceptCommitAtomicRequests applies, in the same database batch as
+	// CommitBlock's own accept, the shared-memory requests registered for
+	// its parent ProposalBlock [proposalBlockID]. CommitBlock.Accept calls
+	// this, and AbortBlock.Accept never does -- which is what ties the
+	// reward/import to the side of the proposal that actually gets
+	// enacted, instead of to ProposalBlock.Accept firing regardless of
+	// which child consensus later decides.
+	acceptCommitAtomicRequests(proposalBlockID ids.ID, db commitBatcher, sharedMemory atomic.SharedMemory) error
+
+	// discardProposalAtomicRequests drops any shared-memory requests
+	// registered for ProposalBlock [proposalBlockID] without applying
+	// them. AbortBlock.Accept calls this, and ProposalBlock.Reject calls it
+	// too so a proposal that never reaches a decided child doesn't leak an
+	// entry.
+	discardProposalAtomicRequests(proposalBlockID ids.ID)
+
+	// Health reports this manager's view of P-chain consensus liveness. It
+	// is consumed by the /ext/bc/P/health endpoint.
+	Health() (interface{}, error)
+
+	// BuildConflictingProposals builds several sibling ProposalBlocks that
+	// share a parent but carry mutually-exclusive proposals. It exists
+	// mainly to exercise conflict handling in tests, where
+	// ProposalBlock.Options alone only ever produces a single proposal's
+	// (commit, abort) pair.
+	BuildConflictingProposals(
+		txExecutorBackend executor.Backend,
+		parentID ids.ID,
+		height uint64,
+		conflictingTxs []*txs.Tx,
+	) ([]*ProposalBlock, ids.Set, error)
+
+	// SetPreference records the ProposalBlock this node currently prefers to
+	// build on, e.g. in response to the consensus engine's own
+	// SetPreference call, so Health can detect when this preference has
+	// forked away from what the node has already accepted.
+	SetPreference(blkID ids.ID)
+}
+
+// HealthConfig bounds how stale a ProposalBlock's diffs may get before
+// Health reports this manager as unhealthy.
+type HealthConfig struct {
+	// MaxTimeSinceAcceptedProposal is how long the manager may go without
+	// accepting a ProposalBlock before it is considered unhealthy.
+	MaxTimeSinceAcceptedProposal time.Duration
+	// MaxProcessingBlocks bounds how many blocks may be outstanding
+	// (verified but not yet decided) before it is considered unhealthy.
+	MaxProcessingBlocks int
+}
+
+// HealthReport is the JSON body returned by Manager.Health.
+type HealthReport struct {
+	TimeSinceLastAcceptedProposal time.Duration `json:"timeSinceLastAcceptedProposal"`
+	ProcessingBlocks              int           `json:"processingBlocks"`
+	DiffsAppliedWithinWindow      bool          `json:"diffsAppliedWithinWindow"`
+	PreferenceAgreement           bool          `json:"preferenceAgreement"`
+}
+
+// manager is the concrete Manager implementation shared by every stateful
+// block type.
+type manager struct {
+	healthConfig HealthConfig
+
+	lock                          sync.RWMutex
+	lastAcceptedProposalBlockTime time.Time
+	processingBlocks              map[ids.ID]struct{}
+	// preferenceAgreement is true when this manager's preferred chain of
+	// ProposalBlocks matches the last block it accepted, i.e. consensus
+	// hasn't forked away from what this node already committed to disk. It
+	// defaults to true: until SetPreference has ever been called, there is
+	// no recorded preference to have forked away from.
+	preferenceAgreement bool
+	preferredID         ids.ID
+	preferredSet        bool
+
+	// pendingAtomicRequests holds, by ProposalBlock ID, the shared-memory
+	// requests that block's proposal produced, from the time it's verified
+	// until whichever of its Commit/Abort children is decided applies or
+	// discards them.
+	pendingAtomicRequests map[ids.ID]map[ids.ID]*atomic.Requests
+}
+
+// NewManager creates a Manager with no accepted blocks and nothing in
+// flight. [healthConfig] bounds how stale Health allows this manager's view
+// of P-chain consensus liveness to get before it reports unhealthy.
+func NewManager(healthConfig HealthConfig) Manager {
+	return &manager{
+		healthConfig:          healthConfig,
+		processingBlocks:      make(map[ids.ID]struct{}),
+		preferenceAgreement:   true,
+		pendingAtomicRequests: make(map[ids.ID]map[ids.ID]*atomic.Requests),
+	}
+}
+
+// Health reports whether P-chain consensus is making progress: blocks are
+// being accepted recently, the number of in-flight blocks isn't growing
+// without bound, onCommit/onAbort diffs aren't falling behind, and this
+// node's preference agrees with what it has already accepted.
+func (m *manager) Health() (interface{}, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+
+	timeSinceAccepted := time.Since(m.lastAcceptedProposalBlockTime)
+	withinWindow := timeSinceAccepted <= m.healthConfig.MaxTimeSinceAcceptedProposal
+	report := HealthReport{
+		TimeSinceLastAcceptedProposal: timeSinceAccepted,
+		ProcessingBlocks:              len(m.processingBlocks),
+		DiffsAppliedWithinWindow:      withinWindow,
+		PreferenceAgreement:           m.preferenceAgreement,
+	}
+
+	if !withinWindow {
+		return report, fmt.Errorf(
+			"no ProposalBlock accepted in %s, exceeding staleness window of %s",
+			timeSinceAccepted,
+			m.healthConfig.MaxTimeSinceAcceptedProposal,
+		)
+	}
+	if max := m.healthConfig.MaxProcessingBlocks; max > 0 && len(m.processingBlocks) > max {
+		return report, fmt.Errorf(
+			"%d blocks processing, exceeding limit of %d",
+			len(m.processingBlocks),
+			max,
+		)
+	}
+	if !m.preferenceAgreement {
+		return report, fmt.Errorf("preferred chain disagrees with last accepted block")
+	}
+	return report, nil
+}
+
+// verifyProposalBlock marks [b] as processing (verified but not yet
+// decided), so Health can bound how many such blocks are outstanding.
+func (m *manager) verifyProposalBlock(b *ProposalBlock) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.processingBlocks[b.ID()] = struct{}{}
+	return nil
+}
+
+// acceptProposalBlock records [b] as this manager's most recently accepted
+// ProposalBlock, which is what Health's staleness and preference-agreement
+// checks are measured against.
+func (m *manager) acceptProposalBlock(b *ProposalBlock) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.processingBlocks, b.ID())
+	m.lastAcceptedProposalBlockTime = time.Now()
+	m.preferenceAgreement = !m.preferredSet || m.preferredID == b.ID()
+	return nil
+}
+
+// rejectProposalBlock stops tracking [b] as processing; a rejected block
+// never contributes to lastAcceptedProposalBlockTime or preferenceAgreement.
+func (m *manager) rejectProposalBlock(b *ProposalBlock) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.processingBlocks, b.ID())
+	return nil
+}
+
+// conflictsProposalBlock reports whether [b]'s tx is in the conflict set
+// [s], e.g. because it double-spends a UTXO that a sibling in [s] already
+// spent.
+func (m *manager) conflictsProposalBlock(b *ProposalBlock, s ids.Set) (bool, error) {
+	return s.Contains(b.Tx.ID()), nil
+}
+
+// freeProposalBlock stops tracking [b] as processing without deciding it,
+// e.g. when it is evicted from memory for being too far behind the
+// preferred chain to ever be decided.
+func (m *manager) freeProposalBlock(b *ProposalBlock) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.processingBlocks, b.ID())
+}
+
+func (m *manager) setBaseStateProposalBlock(b *ProposalBlock) {}
+
+// registerProposalAtomicRequests implements Manager.
+func (m *manager) registerProposalAtomicRequests(proposalBlockID ids.ID, requests map[ids.ID]*atomic.Requests) {
+	if len(requests) == 0 {
+		return
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.pendingAtomicRequests == nil {
+		m.pendingAtomicRequests = make(map[ids.ID]map[ids.ID]*atomic.Requests)
+	}
+	m.pendingAtomicRequests[proposalBlockID] = requests
+}
+
+// acceptCommitAtomicRequests implements Manager.
+func (m *manager) acceptCommitAtomicRequests(proposalBlockID ids.ID, db commitBatcher, sharedMemory atomic.SharedMemory) error {
+	m.lock.Lock()
+	requests, ok := m.pendingAtomicRequests[proposalBlockID]
+	delete(m.pendingAtomicRequests, proposalBlockID)
+	m.lock.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return applyAtomicRequests(db, sharedMemory, requests)
+}
+
+// discardProposalAtomicRequests implements Manager.
+func (m *manager) discardProposalAtomicRequests(proposalBlockID ids.ID) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	delete(m.pendingAtomicRequests, proposalBlockID)
+}
+
+// SetPreference implements Manager.
+//
+// TODO: wire this up to the consensus engine's own SetPreference calls once
+// the engine integration lands; until then preferredSet stays false and
+// preferenceAgreement stays true.
+func (m *manager) SetPreference(blkID ids.ID) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.preferredID = blkID
+	m.preferredSet = true
+}
@@ -0,0 +1,110 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package stateful
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs/executor"
+)
+
+func newTestProposalBlock(t *testing.T, mgr Manager, parentID ids.ID, height uint64) *ProposalBlock {
+	t.Helper()
+
+	tx := &txs.Tx{Unsigned: &testStakerTx{txID: ids.GenerateTestID()}}
+	pb, err := NewProposalBlock(mgr, executor.Backend{}, parentID, height, tx)
+	if err != nil {
+		t.Fatalf("failed to build proposal block: %s", err)
+	}
+	return pb
+}
+
+func TestManagerHealthBeforeAnyAccept(t *testing.T) {
+	mgr := NewManager(HealthConfig{MaxTimeSinceAcceptedProposal: time.Hour})
+
+	if _, err := mgr.Health(); err == nil {
+		t.Fatal("expected Health to fail before any ProposalBlock has been accepted")
+	}
+}
+
+func TestManagerHealthTracksAcceptedProposal(t *testing.T) {
+	mgr := NewManager(HealthConfig{MaxTimeSinceAcceptedProposal: time.Hour})
+	pb := newTestProposalBlock(t, mgr, ids.GenerateTestID(), 1)
+
+	if err := pb.Verify(); err != nil {
+		t.Fatalf("verify failed: %s", err)
+	}
+	if err := pb.Accept(); err != nil {
+		t.Fatalf("accept failed: %s", err)
+	}
+
+	report, err := mgr.Health()
+	if err != nil {
+		t.Fatalf("expected manager to be healthy after accepting a proposal block: %s", err)
+	}
+	hr, ok := report.(HealthReport)
+	if !ok {
+		t.Fatalf("expected a HealthReport, got %T", report)
+	}
+	if hr.TimeSinceLastAcceptedProposal > time.Minute {
+		t.Fatalf("expected a fresh accept timestamp, got %s old", hr.TimeSinceLastAcceptedProposal)
+	}
+}
+
+func TestManagerHealthTracksProcessingBlocks(t *testing.T) {
+	mgr := NewManager(HealthConfig{MaxTimeSinceAcceptedProposal: time.Hour, MaxProcessingBlocks: 1})
+
+	// Accept a seed block first so the staleness check doesn't mask the
+	// processing-block check below.
+	seed := newTestProposalBlock(t, mgr, ids.GenerateTestID(), 1)
+	if err := seed.Verify(); err != nil {
+		t.Fatalf("verify failed: %s", err)
+	}
+	if err := seed.Accept(); err != nil {
+		t.Fatalf("accept failed: %s", err)
+	}
+
+	pb0 := newTestProposalBlock(t, mgr, seed.ID(), 2)
+	pb1 := newTestProposalBlock(t, mgr, seed.ID(), 2)
+	for _, pb := range []*ProposalBlock{pb0, pb1} {
+		if err := pb.Verify(); err != nil {
+			t.Fatalf("verify failed: %s", err)
+		}
+	}
+
+	if _, err := mgr.Health(); err == nil {
+		t.Fatal("expected Health to fail once processing blocks (2) exceed the configured limit (1)")
+	}
+
+	if err := pb0.Accept(); err != nil {
+		t.Fatalf("accept failed: %s", err)
+	}
+	if err := pb1.Reject(); err != nil {
+		t.Fatalf("reject failed: %s", err)
+	}
+
+	if _, err := mgr.Health(); err != nil {
+		t.Fatalf("expected manager to be healthy once the outstanding block was decided: %s", err)
+	}
+}
+
+func TestManagerHealthDetectsPreferenceDisagreement(t *testing.T) {
+	mgr := NewManager(HealthConfig{MaxTimeSinceAcceptedProposal: time.Hour})
+	mgr.SetPreference(ids.GenerateTestID()) // prefers a block other than the one below
+
+	pb := newTestProposalBlock(t, mgr, ids.GenerateTestID(), 1)
+	if err := pb.Verify(); err != nil {
+		t.Fatalf("verify failed: %s", err)
+	}
+	if err := pb.Accept(); err != nil {
+		t.Fatalf("accept failed: %s", err)
+	}
+
+	if _, err := mgr.Health(); err == nil {
+		t.Fatal("expected Health to fail when the accepted block differs from this node's preference")
+	}
+}
@@ -6,6 +6,8 @@ package stateful
 import (
 	"fmt"
 
+	"github.com/ava-labs/avalanchego/chains/atomic"
+	"github.com/ava-labs/avalanchego/database"
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/avalanchego/snow/choices"
 	"github.com/ava-labs/avalanchego/snow/consensus/snowman"
@@ -15,12 +17,28 @@ import (
 	"github.com/ava-labs/avalanchego/vms/platformvm/txs/executor"
 )
 
+// atomicTx is implemented by proposal txs that need to publish side effects
+// to another chain's shared memory atomically with this block's acceptance,
+// e.g. *RewardValidatorTx crediting a reward's outputs to the X-chain.
+type atomicTx interface {
+	// AtomicOperations returns the shared memory requests this tx produces,
+	// keyed by the chain they should be applied to. A nil map means the tx
+	// has no atomic side effects.
+	AtomicOperations() (map[ids.ID]*atomic.Requests, error)
+}
+
+// commitBatcher is the subset of the VM's database a ProposalBlock needs in
+// order to commit its accept batch.
+type commitBatcher interface {
+	CommitBatch() (database.Batch, error)
+}
+
 var _ Block = &ProposalBlock{}
 
 // ProposalBlock is a proposal to change the chain's state.
 //
 // A proposal may be to:
-// 	1. Advance the chain's timestamp (*AdvanceTimeTx)
+//  1. Advance the chain's timestamp (*AdvanceTimeTx)
 //  2. Remove a staker from the staker set (*RewardStakerTx)
 //  3. Add a new staker to the set of pending (future) stakers
 //     (*AddValidatorTx, *AddDelegatorTx, *AddSubnetValidatorTx)
@@ -38,6 +56,14 @@ type ProposalBlock struct {
 	// The state that the chain will have if this block's proposal is aborted
 	onAbortState  state.Diff
 	prefersCommit bool
+
+	// atomicRequests are the shared memory operations, keyed by chain, that
+	// this block's proposal tx would need applied if it's enacted. It's
+	// populated during Verify and kept here for inspection; the copy that
+	// actually gets applied or discarded is the one registered with
+	// Manager, since it's whichever of this block's Commit/Abort children
+	// is accepted -- never this block's own Accept -- that decides which.
+	atomicRequests map[ids.ID]*atomic.Requests
 }
 
 // NewProposalBlock creates a new block that proposes to issue a transaction.
@@ -91,16 +117,68 @@ func (pb *ProposalBlock) free() {
 //
 // The parent block must either be a Commit or an Abort block.
 //
-// If this block is valid, this function also sets pas.onCommit and pas.onAbort.
+// Validity is decided by the Manager's verifyProposalBlock. If this block
+// is valid, this function also registers any atomic shared-memory requests
+// this block's proposal tx produces with the Manager, so whichever of
+// Commit/Abort ends up accepted can apply or discard them. It does not set
+// onCommitState or onAbortState.
 func (pb *ProposalBlock) Verify() error {
-	return pb.verifyProposalBlock(pb)
+	if err := pb.verifyProposalBlock(pb); err != nil {
+		return err
+	}
+
+	atomicTx, ok := pb.Tx.Unsigned.(atomicTx)
+	if !ok {
+		return nil
+	}
+	atomicRequests, err := atomicTx.AtomicOperations()
+	if err != nil {
+		return fmt.Errorf("failed computing atomic requests for %s: %w", pb.ID(), err)
+	}
+	pb.atomicRequests = atomicRequests
+	pb.registerProposalAtomicRequests(pb.ID(), atomicRequests)
+	return nil
 }
 
+// Accept records this block as accepted through the Manager's
+// acceptProposalBlock.
+//
+// This does NOT apply this block's atomic shared-memory requests: the
+// proposal they came from is only enacted if this ProposalBlock's Commit
+// child -- not its Abort child -- is the one consensus later accepts, so
+// CommitBlock.Accept is what applies them (via
+// Manager.acceptCommitAtomicRequests), never ProposalBlock.Accept.
 func (pb *ProposalBlock) Accept() error {
 	return pb.acceptProposalBlock(pb)
 }
 
+// applyAtomicRequests takes [db]'s pending writes as a batch and hands that
+// batch, together with [requests], to [sharedMemory]. SharedMemory.Apply is
+// what performs the batch write, so either both the local changes and the
+// cross-chain side effect land together, or (if the process dies before
+// Apply writes, including between the two calls here, or Apply itself
+// errors) neither does.
+func applyAtomicRequests(db commitBatcher, sharedMemory atomic.SharedMemory, requests map[ids.ID]*atomic.Requests) error {
+	if len(requests) == 0 {
+		return nil
+	}
+
+	batch, err := db.CommitBatch()
+	if err != nil {
+		return fmt.Errorf("failed to commit VM's database: %w", err)
+	}
+	if err := sharedMemory.Apply(requests, batch); err != nil {
+		return fmt.Errorf("failed to apply atomic requests: %w", err)
+	}
+	return nil
+}
+
+// Reject discards this block's proposed state changes. Any atomic requests
+// that were computed during Verify are dropped, both locally and from the
+// Manager, along with them so they are never applied to shared memory.
 func (pb *ProposalBlock) Reject() error {
+	pb.atomicRequests = nil
+	pb.discardProposalAtomicRequests(pb.ID())
 	return pb.rejectProposalBlock(pb)
 }
 
@@ -0,0 +1,195 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package stateful
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs/executor"
+)
+
+// newTestRewardValidatorTx returns a *txs.Tx wrapping a *txs.RewardValidatorTx
+// that credits [rewardUTXOBytes] to rewardChainID, the atomicTx that
+// ProposalBlock's Verify/Accept wiring is built around.
+func newTestRewardValidatorTx(rewardChainID ids.ID, rewardUTXOBytes []byte) *txs.Tx {
+	return &txs.Tx{
+		Unsigned: &txs.RewardValidatorTx{
+			TxID:            ids.GenerateTestID(),
+			RewardChainID:   rewardChainID,
+			RewardUTXOID:    ids.GenerateTestID(),
+			RewardUTXOBytes: rewardUTXOBytes,
+		},
+	}
+}
+
+// TestProposalBlockAppliesAtomicRequestsOnlyOnCommit drives a real atomicTx
+// (*txs.RewardValidatorTx) through Verify and Accept and checks that the
+// reward it produces is NOT applied by ProposalBlock.Accept itself, only
+// once the Manager is told (as CommitBlock.Accept would) that this
+// proposal's Commit child is the one that got accepted.
+func TestProposalBlockAppliesAtomicRequestsOnlyOnCommit(t *testing.T) {
+	rewardChainID := ids.GenerateTestID()
+	tx := newTestRewardValidatorTx(rewardChainID, []byte("reward"))
+
+	db := &fakeCommitBatcher{}
+	sm := &fakeSharedMemory{}
+	backend := executor.Backend{
+		DB:  db,
+		Ctx: &snow.Context{SharedMemory: sm},
+	}
+
+	mgr := NewManager(HealthConfig{MaxTimeSinceAcceptedProposal: time.Hour})
+	pb, err := NewProposalBlock(mgr, backend, ids.GenerateTestID(), 1, tx)
+	if err != nil {
+		t.Fatalf("failed to build proposal block: %s", err)
+	}
+
+	if err := pb.Verify(); err != nil {
+		t.Fatalf("verify failed: %s", err)
+	}
+	if pb.atomicRequests == nil {
+		t.Fatal("expected Verify to populate atomicRequests for an atomicTx")
+	}
+
+	if err := pb.Accept(); err != nil {
+		t.Fatalf("accept failed: %s", err)
+	}
+	if db.commits != 0 || sm.applied != nil {
+		t.Fatal("expected ProposalBlock.Accept to never itself apply atomic requests")
+	}
+
+	// Simulate CommitBlock.Accept: it's the side that actually enacts the
+	// proposal, so it's what tells the manager to apply the reward.
+	if err := mgr.acceptCommitAtomicRequests(pb.ID(), db, sm); err != nil {
+		t.Fatalf("acceptCommitAtomicRequests failed: %s", err)
+	}
+	if db.commits != 1 {
+		t.Fatalf("expected exactly one batch commit, got %d", db.commits)
+	}
+	if len(sm.applied) != 1 || sm.applied[rewardChainID] == nil {
+		t.Fatal("expected the reward to be applied to shared memory once Commit is accepted")
+	}
+}
+
+// TestProposalBlockDropsAtomicRequestsWhenAbortWins checks that a
+// ProposalBlock's atomic requests never reach shared memory when its Abort
+// child -- not Commit -- is the one consensus accepts.
+func TestProposalBlockDropsAtomicRequestsWhenAbortWins(t *testing.T) {
+	rewardChainID := ids.GenerateTestID()
+	tx := newTestRewardValidatorTx(rewardChainID, []byte("reward"))
+
+	db := &fakeCommitBatcher{}
+	sm := &fakeSharedMemory{}
+	backend := executor.Backend{
+		DB:  db,
+		Ctx: &snow.Context{SharedMemory: sm},
+	}
+
+	mgr := NewManager(HealthConfig{MaxTimeSinceAcceptedProposal: time.Hour})
+	pb, err := NewProposalBlock(mgr, backend, ids.GenerateTestID(), 1, tx)
+	if err != nil {
+		t.Fatalf("failed to build proposal block: %s", err)
+	}
+
+	if err := pb.Verify(); err != nil {
+		t.Fatalf("verify failed: %s", err)
+	}
+	if err := pb.Accept(); err != nil {
+		t.Fatalf("accept failed: %s", err)
+	}
+
+	// Simulate AbortBlock.Accept: it discards the registered requests
+	// instead of applying them.
+	mgr.discardProposalAtomicRequests(pb.ID())
+
+	// Even if something were to (incorrectly) try to apply them again, the
+	// manager no longer has anything registered for this proposal.
+	if err := mgr.acceptCommitAtomicRequests(pb.ID(), db, sm); err != nil {
+		t.Fatalf("acceptCommitAtomicRequests failed: %s", err)
+	}
+	if db.commits != 0 {
+		t.Fatalf("expected no batch commit once Abort won, got %d", db.commits)
+	}
+	if sm.applied != nil {
+		t.Fatal("expected the reward to never reach shared memory once Abort won")
+	}
+}
+
+// TestProposalBlockDropsAtomicRequestsOnReject checks that rejecting a
+// ProposalBlock whose proposal produced atomic requests never applies them
+// to shared memory, and discards the manager's registered copy too.
+func TestProposalBlockDropsAtomicRequestsOnReject(t *testing.T) {
+	rewardChainID := ids.GenerateTestID()
+	tx := newTestRewardValidatorTx(rewardChainID, []byte("reward"))
+
+	db := &fakeCommitBatcher{}
+	sm := &fakeSharedMemory{}
+	backend := executor.Backend{
+		DB:  db,
+		Ctx: &snow.Context{SharedMemory: sm},
+	}
+
+	mgr := NewManager(HealthConfig{MaxTimeSinceAcceptedProposal: time.Hour})
+	pb, err := NewProposalBlock(mgr, backend, ids.GenerateTestID(), 1, tx)
+	if err != nil {
+		t.Fatalf("failed to build proposal block: %s", err)
+	}
+
+	if err := pb.Verify(); err != nil {
+		t.Fatalf("verify failed: %s", err)
+	}
+	if err := pb.Reject(); err != nil {
+		t.Fatalf("reject failed: %s", err)
+	}
+
+	if err := mgr.acceptCommitAtomicRequests(pb.ID(), db, sm); err != nil {
+		t.Fatalf("acceptCommitAtomicRequests failed: %s", err)
+	}
+	if db.commits != 0 {
+		t.Fatalf("expected no batch commit for a rejected block, got %d", db.commits)
+	}
+	if sm.applied != nil {
+		t.Fatal("expected a rejected block's atomic requests to never reach shared memory")
+	}
+	if pb.atomicRequests != nil {
+		t.Fatal("expected Reject to clear atomicRequests")
+	}
+}
+
+// TestProposalBlockNoAtomicRequestsForNonAtomicTx checks that Verify leaves
+// atomicRequests nil, and Accept never touches shared memory, for a
+// proposal tx that isn't an atomicTx.
+func TestProposalBlockNoAtomicRequestsForNonAtomicTx(t *testing.T) {
+	db := &fakeCommitBatcher{}
+	sm := &fakeSharedMemory{}
+	backend := executor.Backend{
+		DB:  db,
+		Ctx: &snow.Context{SharedMemory: sm},
+	}
+
+	mgr := NewManager(HealthConfig{MaxTimeSinceAcceptedProposal: time.Hour})
+	tx := &txs.Tx{Unsigned: &testStakerTx{txID: ids.GenerateTestID()}}
+	pb, err := NewProposalBlock(mgr, backend, ids.GenerateTestID(), 1, tx)
+	if err != nil {
+		t.Fatalf("failed to build proposal block: %s", err)
+	}
+
+	if err := pb.Verify(); err != nil {
+		t.Fatalf("verify failed: %s", err)
+	}
+	if pb.atomicRequests != nil {
+		t.Fatal("expected atomicRequests to stay nil for a non-atomicTx proposal")
+	}
+
+	if err := pb.Accept(); err != nil {
+		t.Fatalf("accept failed: %s", err)
+	}
+	if db.commits != 0 {
+		t.Fatal("expected no batch commit when there are no atomic requests")
+	}
+}
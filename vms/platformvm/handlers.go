@@ -0,0 +1,22 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package platformvm
+
+import (
+	"net/http"
+
+	"github.com/ava-labs/avalanchego/vms/platformvm/blocks/stateful"
+)
+
+// createHandlers returns every HTTP route this VM serves under its base
+// endpoint, e.g. /ext/bc/P, keyed by the suffix appended to it. "" is the
+// existing JSON-RPC handler; "/health" mounts newHealthHandler alongside it
+// so /ext/bc/P/health is actually reachable on a running node, instead of
+// newHealthHandler only ever being exercised from its own unit tests.
+func createHandlers(manager stateful.Manager, rpc http.Handler) map[string]http.Handler {
+	return map[string]http.Handler{
+		"":        rpc,
+		"/health": newHealthHandler(manager, rpc),
+	}
+}
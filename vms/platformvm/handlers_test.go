@@ -0,0 +1,36 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package platformvm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/vms/platformvm/blocks/stateful"
+)
+
+func TestCreateHandlersMountsHealthAlongsideRPC(t *testing.T) {
+	manager := &fakeHealthManager{report: stateful.HealthReport{}}
+	rpc := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	handlers := createHandlers(manager, rpc)
+
+	if _, ok := handlers[""]; !ok {
+		t.Fatal("expected the JSON-RPC handler to be mounted at \"\"")
+	}
+	health, ok := handlers["/health"]
+	if !ok {
+		t.Fatal("expected a handler to be mounted at \"/health\"")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/ext/bc/P/health", nil)
+	rec := httptest.NewRecorder()
+	health.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d from the health route, got %d", http.StatusOK, rec.Code)
+	}
+}
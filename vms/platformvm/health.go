@@ -0,0 +1,46 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package platformvm
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ava-labs/avalanchego/vms/platformvm/blocks/stateful"
+)
+
+// newHealthHandler wraps the block Manager's liveness checks in an
+// http.Handler mounted at /ext/bc/P/health. It gives operators a
+// load-balancer-friendly liveness probe specific to consensus progress on
+// the P-chain, rather than only process-level readiness: GET requests
+// return HTTP 200 when all checks pass and HTTP 500 otherwise, while every
+// other verb falls through to the regular JSON-RPC handler mounted
+// alongside it, matching the pattern used by the health service.
+func newHealthHandler(manager stateful.Manager, rpc http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			rpc.ServeHTTP(w, r)
+			return
+		}
+
+		report, err := manager.Health()
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"healthy": false,
+				"error":   err.Error(),
+				"checks":  report,
+			})
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"healthy": true,
+			"checks":  report,
+		})
+	})
+}
@@ -0,0 +1,79 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package platformvm
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ava-labs/avalanchego/vms/platformvm/blocks/stateful"
+)
+
+// fakeHealthManager overrides only Health, promoting every other
+// stateful.Manager method from the embedded (nil) interface so it can stand
+// in for a real Manager without needing to construct one.
+type fakeHealthManager struct {
+	stateful.Manager
+	report interface{}
+	err    error
+}
+
+func (m *fakeHealthManager) Health() (interface{}, error) {
+	return m.report, m.err
+}
+
+func TestHealthHandlerHealthy(t *testing.T) {
+	manager := &fakeHealthManager{report: stateful.HealthReport{}}
+	rpc := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		t.Fatal("rpc handler should not be invoked for a GET request")
+	})
+
+	handler := newHealthHandler(manager, rpc)
+	req := httptest.NewRequest(http.MethodGet, "/ext/bc/P/health", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+}
+
+func TestHealthHandlerUnhealthy(t *testing.T) {
+	manager := &fakeHealthManager{err: errors.New("no ProposalBlock accepted recently")}
+	rpc := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		t.Fatal("rpc handler should not be invoked for a GET request")
+	})
+
+	handler := newHealthHandler(manager, rpc)
+	req := httptest.NewRequest(http.MethodGet, "/ext/bc/P/health", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status %d, got %d", http.StatusInternalServerError, rec.Code)
+	}
+}
+
+func TestHealthHandlerFallsThroughForNonGet(t *testing.T) {
+	manager := &fakeHealthManager{report: stateful.HealthReport{}}
+	called := false
+	rpc := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	})
+
+	handler := newHealthHandler(manager, rpc)
+	req := httptest.NewRequest(http.MethodPost, "/ext/bc/P", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if !called {
+		t.Fatal("expected non-GET requests to fall through to the rpc handler")
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the rpc handler's response to pass through, got status %d", rec.Code)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package txheap
+
+import (
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+)
+
+type byEndTime struct {
+	*txHeap
+}
+
+// NewTxHeapByEndTime returns a new, empty TxHeap, with its own private
+// dedup cache, that pops txs in ascending order of EndTime.
+func NewTxHeapByEndTime() TxHeap {
+	return NewTxHeapByEndTimeWithCache(NewUniqueTxCache())
+}
+
+// NewTxHeapByEndTimeWithCache is like NewTxHeapByEndTime, but dedupes
+// through [cache] instead of a private one, so this heap can share one
+// dedup layer with other TxHeaps built over the same cache.
+func NewTxHeapByEndTimeWithCache(cache *UniqueTxCache) TxHeap {
+	h := &byEndTime{
+		txHeap: &txHeap{
+			cache: cache,
+			index: make(map[ids.ID]*entry),
+		},
+	}
+	h.txHeap.less = func(a, b timedTx) bool {
+		return a.EndTime().Before(b.EndTime())
+	}
+	return h
+}
+
+func (h *byEndTime) Add(tx *txs.Tx) *UniqueTx { return h.add(tx) }
+
+func (h *byEndTime) Peek() *txs.Tx {
+	top := h.peek()
+	if top == nil {
+		return nil
+	}
+	return top.Tx()
+}
+
+func (h *byEndTime) Remove() *txs.Tx {
+	top := h.remove()
+	if top == nil {
+		return nil
+	}
+	return top.Tx()
+}
+
+func (h *byEndTime) RemoveTx(txID ids.ID) { h.removeTx(txID) }
+
+func (h *byEndTime) Timestamp() time.Time {
+	top := h.Peek()
+	if top == nil {
+		return time.Time{}
+	}
+	return top.Unsigned.(timedTx).EndTime()
+}
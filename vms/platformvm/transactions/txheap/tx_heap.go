@@ -0,0 +1,133 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package txheap
+
+import (
+	"container/heap"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+)
+
+// TxHeap orders staker txs by one of their timestamps (start or end time).
+// Every implementation in this package routes Add/Peek/Remove through a
+// single UniqueTx cache, so the platformvm mempool and block builders can
+// share one heap without scheduling the same tx twice.
+type TxHeap interface {
+	// Add [tx] to the heap, returning the canonical *UniqueTx that now
+	// represents it. If [tx] (by ID) is already on the heap, the existing
+	// entry is left in place and no duplicate is scheduled.
+	Add(tx *txs.Tx) *UniqueTx
+	// Peek returns, without removing, the tx at the top of the heap.
+	Peek() *txs.Tx
+	// Remove removes and returns the tx at the top of the heap.
+	Remove() *txs.Tx
+	// RemoveTx removes [txID] from the heap, if present, and evicts it from
+	// the shared UniqueTx cache.
+	RemoveTx(txID ids.ID)
+	// Timestamp returns the timestamp of the tx at the top of the heap, or
+	// the zero time if the heap is empty.
+	Timestamp() time.Time
+	Len() int
+}
+
+// timedTx is implemented by the staker txs that can be placed on a TxHeap.
+type timedTx interface {
+	StartTime() time.Time
+	EndTime() time.Time
+}
+
+// entry is a single slot in the underlying container/heap.
+type entry struct {
+	tx    *UniqueTx
+	timed timedTx
+	index int
+}
+
+// txHeap is the shared container/heap.Interface implementation driving both
+// byStartTime and byEndTime; only the ordering function differs between
+// them.
+type txHeap struct {
+	cache   *UniqueTxCache
+	entries []*entry
+	index   map[ids.ID]*entry
+	less    func(a, b timedTx) bool
+}
+
+func (h *txHeap) Len() int { return len(h.entries) }
+
+func (h *txHeap) Less(i, j int) bool {
+	return h.less(h.entries[i].timed, h.entries[j].timed)
+}
+
+func (h *txHeap) Swap(i, j int) {
+	h.entries[i], h.entries[j] = h.entries[j], h.entries[i]
+	h.entries[i].index = i
+	h.entries[j].index = j
+}
+
+func (h *txHeap) Push(x interface{}) {
+	e := x.(*entry)
+	e.index = len(h.entries)
+	h.entries = append(h.entries, e)
+	h.index[e.tx.id] = e
+}
+
+func (h *txHeap) Pop() interface{} {
+	n := len(h.entries)
+	e := h.entries[n-1]
+	h.entries[n-1] = nil
+	h.entries = h.entries[:n-1]
+	delete(h.index, e.tx.id)
+	return e
+}
+
+// add deduplicates [tx] through the shared cache and, the first time this
+// tx's ID is seen, pushes it onto the heap. A tx whose Unsigned doesn't
+// implement timedTx can never be scheduled on this heap, so it's evicted
+// from the cache immediately rather than left as an entry no Remove/RemoveTx
+// will ever reach.
+func (h *txHeap) add(tx *txs.Tx) *UniqueTx {
+	unique := h.cache.deduplicate(tx)
+	if _, ok := h.index[unique.id]; ok {
+		return unique
+	}
+
+	timed, ok := unique.Tx().Unsigned.(timedTx)
+	if !ok {
+		unique.Evict()
+		return unique
+	}
+
+	heap.Push(h, &entry{tx: unique, timed: timed})
+	return unique
+}
+
+func (h *txHeap) peek() *UniqueTx {
+	if len(h.entries) == 0 {
+		return nil
+	}
+	top := h.entries[0].tx
+	h.cache.refresh(top)
+	return top
+}
+
+func (h *txHeap) remove() *UniqueTx {
+	if len(h.entries) == 0 {
+		return nil
+	}
+	e := heap.Pop(h).(*entry)
+	e.tx.Evict()
+	return e.tx
+}
+
+func (h *txHeap) removeTx(txID ids.ID) {
+	e, ok := h.index[txID]
+	if !ok {
+		return
+	}
+	heap.Remove(h, e.index)
+	e.tx.Evict()
+}
@@ -0,0 +1,150 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package txheap
+
+import (
+	"sync"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow/choices"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+)
+
+// txState is the subset of platformvm chain state a UniqueTx needs in order
+// to refresh its on-disk status.
+type txState interface {
+	GetTx(txID ids.ID) (*txs.Tx, choices.Status, error)
+}
+
+// UniqueTx wraps a *txs.Tx so that every caller asking the shared
+// UniqueTxCache for the same tx.ID() gets back the exact same instance. This
+// is the spdagvm-style dedup pattern: without it, the same tx pushed twice
+// (once from mempool re-gossip, once from a block-building retry) would be
+// scheduled, and fee-accounted for, twice.
+type UniqueTx struct {
+	cache *UniqueTxCache
+	id    ids.ID
+
+	lock   sync.RWMutex
+	tx     *txs.Tx
+	status choices.Status
+}
+
+// ID returns the ID of the wrapped tx.
+func (tx *UniqueTx) ID() ids.ID { return tx.id }
+
+// Tx returns the wrapped tx.
+func (tx *UniqueTx) Tx() *txs.Tx {
+	tx.lock.RLock()
+	defer tx.lock.RUnlock()
+	return tx.tx
+}
+
+// Status returns this tx's status as of the last refresh.
+func (tx *UniqueTx) Status() choices.Status {
+	tx.lock.RLock()
+	defer tx.lock.RUnlock()
+	return tx.status
+}
+
+// refresh reloads this tx's status from [s]. It is a no-op if the tx is
+// unknown to state, which just means it hasn't been accepted or rejected
+// yet.
+func (tx *UniqueTx) refresh(s txState) {
+	loaded, status, err := s.GetTx(tx.id)
+	if err != nil {
+		return
+	}
+
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+	tx.tx = loaded
+	tx.status = status
+}
+
+// Evict invalidates this tx's cache entry so that the next Add for the same
+// ID allocates a fresh instance rather than returning this one. Heaps call
+// this once a tx has been removed, e.g. after it is Accepted or Rejected.
+func (tx *UniqueTx) Evict() {
+	tx.cache.evict(tx.id)
+}
+
+// UniqueTxCache canonicalizes txs by ID so every TxHeap sharing it sees the
+// same in-memory instance for a given tx. It is safe for concurrent use.
+//
+// Construct one with NewUniqueTxCache and pass it to every
+// NewTxHeapByStartTimeWithCache/NewTxHeapByEndTimeWithCache call that should
+// dedupe against each other — e.g. the platformvm mempool's heap and a
+// block builder's heap, so a tx re-gossiped into the mempool while the
+// builder is retrying a block doesn't get scheduled twice.
+type UniqueTxCache struct {
+	lock  sync.Mutex
+	txs   map[ids.ID]*UniqueTx
+	state txState
+}
+
+// NewUniqueTxCache returns a new, empty UniqueTxCache that never refreshes
+// status from state. Use NewUniqueTxCacheWithState for a cache whose
+// entries stay in sync with on-disk status.
+func NewUniqueTxCache() *UniqueTxCache {
+	return NewUniqueTxCacheWithState(nil)
+}
+
+// NewUniqueTxCacheWithState returns a new, empty UniqueTxCache that
+// refreshes a tx's status from [state] every time it is looked up, so a tx
+// accepted or rejected through one heap sharing this cache is reflected the
+// next time any other heap peeks or re-adds it.
+func NewUniqueTxCacheWithState(state txState) *UniqueTxCache {
+	return &UniqueTxCache{
+		txs:   make(map[ids.ID]*UniqueTx),
+		state: state,
+	}
+}
+
+// deduplicate returns the canonical *UniqueTx for [tx], creating one the
+// first time its ID is seen and returning the existing entry otherwise. The
+// returned entry is refreshed from state first, so a tx already decided
+// elsewhere (e.g. accepted through a different heap sharing this cache) is
+// reflected before the caller schedules it.
+func (c *UniqueTxCache) deduplicate(tx *txs.Tx) *UniqueTx {
+	txID := tx.Unsigned.ID()
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	unique, ok := c.txs[txID]
+	if !ok {
+		unique = &UniqueTx{
+			cache: c,
+			id:    txID,
+			tx:    tx,
+		}
+		c.txs[txID] = unique
+	}
+	if c.state != nil {
+		unique.refresh(c.state)
+	}
+	return unique
+}
+
+// refresh reloads [tx]'s status from this cache's txState, if it has one.
+// It is a no-op for caches built without a txState.
+func (c *UniqueTxCache) refresh(tx *UniqueTx) {
+	if c.state == nil {
+		return
+	}
+	tx.refresh(c.state)
+}
+
+func (c *UniqueTxCache) evict(txID ids.ID) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.txs, txID)
+}
+
+func (c *UniqueTxCache) len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.txs)
+}
@@ -0,0 +1,181 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package txheap
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow"
+	"github.com/ava-labs/avalanchego/snow/choices"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
+)
+
+// fakeTxState is a minimal txState fixture that reports [status] for [id]
+// and errors for every other tx, standing in for a tx that state doesn't
+// know about yet.
+type fakeTxState struct {
+	id     ids.ID
+	tx     *txs.Tx
+	status choices.Status
+}
+
+func (s *fakeTxState) GetTx(txID ids.ID) (*txs.Tx, choices.Status, error) {
+	if txID != s.id {
+		return nil, choices.Unknown, errors.New("unknown tx")
+	}
+	return s.tx, s.status, nil
+}
+
+// untimedTx is a txs.UnsignedTx fixture that doesn't implement timedTx, so
+// a TxHeap can never schedule it.
+type untimedTx struct {
+	txID ids.ID
+}
+
+func (tx *untimedTx) ID() ids.ID          { return tx.txID }
+func (tx *untimedTx) InitCtx(*snow.Context) {}
+
+func newTestTx(id ids.ID, start time.Time) *txs.Tx {
+	return &txs.Tx{
+		Unsigned: &txs.AddValidatorTx{
+			TxID:  id,
+			Start: start,
+			End:   start.Add(time.Hour),
+		},
+	}
+}
+
+func TestUniqueTxCacheReAdd(t *testing.T) {
+	cache := NewUniqueTxCache()
+	id := ids.GenerateTestID()
+
+	first := cache.deduplicate(newTestTx(id, time.Now()))
+	// Simulate the same tx being re-seen from mempool re-gossip: a second
+	// *txs.Tx value with the same ID, but otherwise distinct.
+	second := cache.deduplicate(newTestTx(id, time.Now().Add(time.Minute)))
+
+	if first != second {
+		t.Fatal("re-adding a tx with the same ID should return the original instance")
+	}
+	if cache.len() != 1 {
+		t.Fatalf("expected 1 cached tx, got %d", cache.len())
+	}
+}
+
+func TestUniqueTxCacheConcurrentAccess(t *testing.T) {
+	cache := NewUniqueTxCache()
+	id := ids.GenerateTestID()
+
+	const numGoroutines = 50
+	results := make([]*UniqueTx, numGoroutines)
+
+	var wg sync.WaitGroup
+	wg.Add(numGoroutines)
+	for i := 0; i < numGoroutines; i++ {
+		i := i
+		go func() {
+			defer wg.Done()
+			results[i] = cache.deduplicate(newTestTx(id, time.Now()))
+		}()
+	}
+	wg.Wait()
+
+	for i := 1; i < numGoroutines; i++ {
+		if results[i] != results[0] {
+			t.Fatal("concurrent Add of the same tx ID should all resolve to the same instance")
+		}
+	}
+	if cache.len() != 1 {
+		t.Fatalf("expected 1 cached tx, got %d", cache.len())
+	}
+}
+
+func TestUniqueTxEvictOnRemove(t *testing.T) {
+	heap := NewTxHeapByStartTime()
+	id := ids.GenerateTestID()
+	tx := newTestTx(id, time.Now())
+
+	heap.Add(tx)
+	if heap.Len() != 1 {
+		t.Fatalf("expected 1 entry after Add, got %d", heap.Len())
+	}
+
+	removed := heap.Remove()
+	if removed == nil || removed.Unsigned.ID() != id {
+		t.Fatal("Remove should return the tx that was added")
+	}
+	if heap.Len() != 0 {
+		t.Fatal("heap should be empty after removing its only entry")
+	}
+
+	// Evict on removal means re-adding the same ID schedules it again
+	// instead of silently returning the stale, already-removed entry.
+	heap.Add(tx)
+	if heap.Len() != 1 {
+		t.Fatal("re-adding after eviction should schedule the tx again")
+	}
+}
+
+func TestTxHeapsShareCache(t *testing.T) {
+	cache := NewUniqueTxCache()
+	mempool := NewTxHeapByStartTimeWithCache(cache)
+	builder := NewTxHeapByStartTimeWithCache(cache)
+
+	tx := newTestTx(ids.GenerateTestID(), time.Now())
+
+	fromMempool := mempool.Add(tx)
+	// The block builder re-discovers the same tx, e.g. retrying a build
+	// after a failed attempt. Because it shares [cache] with the mempool
+	// heap, it must dedupe against the mempool's in-flight copy instead of
+	// scheduling (and fee-accounting for) a second one.
+	fromBuilder := builder.Add(tx)
+
+	if fromMempool != fromBuilder {
+		t.Fatal("heaps sharing a cache should canonicalize the same tx ID to one instance")
+	}
+	if cache.len() != 1 {
+		t.Fatalf("expected 1 cached tx, got %d", cache.len())
+	}
+}
+
+func TestUniqueTxCacheWithStateRefreshesOnLookup(t *testing.T) {
+	id := ids.GenerateTestID()
+	tx := newTestTx(id, time.Now())
+	state := &fakeTxState{id: id, tx: tx, status: choices.Processing}
+	cache := NewUniqueTxCacheWithState(state)
+	h := NewTxHeapByStartTimeWithCache(cache)
+
+	unique := h.Add(tx)
+	if status := unique.Status(); status != choices.Processing {
+		t.Fatalf("expected status Processing after Add, got %s", status)
+	}
+
+	// Simulate the tx being accepted through some other path, e.g. a
+	// sibling heap sharing this cache deciding it first.
+	state.status = choices.Accepted
+	if h.Peek() == nil {
+		t.Fatal("expected Peek to return the tx")
+	}
+	if status := unique.Status(); status != choices.Accepted {
+		t.Fatalf("expected Peek to refresh status to Accepted, got %s", status)
+	}
+}
+
+func TestTxHeapEvictsTxThatCannotBeScheduled(t *testing.T) {
+	cache := NewUniqueTxCache()
+	h := NewTxHeapByStartTimeWithCache(cache)
+	tx := &txs.Tx{Unsigned: &untimedTx{txID: ids.GenerateTestID()}}
+
+	h.Add(tx)
+	if h.Len() != 0 {
+		t.Fatalf("expected a non-timedTx to never be scheduled, heap has %d entries", h.Len())
+	}
+	if cache.len() != 0 {
+		t.Fatalf("expected a non-timedTx to be evicted from the shared cache, got %d cached", cache.len())
+	}
+}
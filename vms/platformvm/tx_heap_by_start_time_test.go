@@ -9,7 +9,7 @@ import (
 	"github.com/ava-labs/avalanchego/ids"
 	"github.com/ava-labs/avalanchego/utils/crypto"
 	"github.com/ava-labs/avalanchego/vms/platformvm/transactions/txheap"
-	"github.com/ava-labs/avalanchego/vms/platformvm/transactions/unsigned"
+	"github.com/ava-labs/avalanchego/vms/platformvm/txs"
 )
 
 // TODO: while heap has been moved to a different package, these UTs are still here since
@@ -40,7 +40,7 @@ func TestTxHeapByStartTime(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	vdr0Tx := validator0.Unsigned.(*unsigned.AddValidatorTx)
+	vdr0Tx := validator0.Unsigned.(*txs.AddValidatorTx)
 
 	validator1, err := vm.newAddValidatorTx(
 		vm.MinValidatorStake,                                               // stake amount
@@ -55,7 +55,7 @@ func TestTxHeapByStartTime(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	vdr1Tx := validator1.Unsigned.(*unsigned.AddValidatorTx)
+	vdr1Tx := validator1.Unsigned.(*txs.AddValidatorTx)
 
 	validator2, err := vm.newAddValidatorTx(
 		vm.MinValidatorStake,                                               // stake amount
@@ -70,7 +70,7 @@ func TestTxHeapByStartTime(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	vdr2Tx := validator2.Unsigned.(*unsigned.AddValidatorTx)
+	vdr2Tx := validator2.Unsigned.(*txs.AddValidatorTx)
 
 	txHeap.Add(validator2)
 	if timestamp := txHeap.Timestamp(); !timestamp.Equal(vdr2Tx.StartTime()) {
@@ -0,0 +1,39 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package txs
+
+import (
+	"time"
+
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow"
+)
+
+var _ UnsignedTx = (*AddValidatorTx)(nil)
+
+// AddValidatorTx adds a new validator to the pending staker set, staking the
+// UTXOs in StakeUTXOIDs for [Start, End).
+type AddValidatorTx struct {
+	TxID         ids.ID    `serialize:"true" json:"txID"`
+	Start        time.Time `serialize:"true" json:"startTime"`
+	End          time.Time `serialize:"true" json:"endTime"`
+	StakeUTXOIDs []ids.ID  `serialize:"true" json:"stakeUTXOIDs"`
+}
+
+func (tx *AddValidatorTx) ID() ids.ID { return tx.TxID }
+
+func (tx *AddValidatorTx) InitCtx(*snow.Context) {}
+
+func (tx *AddValidatorTx) StartTime() time.Time { return tx.Start }
+func (tx *AddValidatorTx) EndTime() time.Time   { return tx.End }
+
+// InputIDs returns the UTXOs this tx stakes. Two AddValidatorTx that list
+// the same UTXO here are double-spends of each other.
+func (tx *AddValidatorTx) InputIDs() ids.Set {
+	inputs := ids.Set{}
+	for _, utxoID := range tx.StakeUTXOIDs {
+		inputs.Add(utxoID)
+	}
+	return inputs
+}
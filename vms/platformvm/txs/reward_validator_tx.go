@@ -0,0 +1,57 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package txs
+
+import (
+	"github.com/ava-labs/avalanchego/chains/atomic"
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow"
+)
+
+var _ UnsignedTx = (*RewardValidatorTx)(nil)
+
+// RewardValidatorTx removes a staker from the current validator set once its
+// staking period ends, crediting its reward (if any) to the staker's reward
+// owner on another chain, e.g. the X-chain.
+type RewardValidatorTx struct {
+	TxID ids.ID `serialize:"true" json:"txID"`
+
+	// RewardChainID is the chain the reward UTXO below should be credited
+	// to.
+	RewardChainID ids.ID `serialize:"true" json:"rewardChainID"`
+	// RewardUTXOID identifies the reward UTXO this tx creates.
+	RewardUTXOID ids.ID `serialize:"true" json:"rewardUTXOID"`
+	// RewardUTXOBytes is the serialized UTXO to credit on RewardChainID. It
+	// is empty when this staker earned no reward, e.g. it was evicted for
+	// misbehavior.
+	RewardUTXOBytes []byte `serialize:"true" json:"rewardUTXOBytes"`
+	// RewardOwnerTraits are the addresses, as shared-memory traits, allowed
+	// to spend the reward UTXO.
+	RewardOwnerTraits [][]byte `serialize:"true" json:"rewardOwnerTraits"`
+}
+
+func (tx *RewardValidatorTx) ID() ids.ID { return tx.TxID }
+
+func (tx *RewardValidatorTx) InitCtx(*snow.Context) {}
+
+// AtomicOperations implements the atomicTx interface stateful.ProposalBlock
+// looks for: it credits this staker's reward UTXO to RewardChainID so it
+// lands in the same database batch as the block's on-chain state changes.
+func (tx *RewardValidatorTx) AtomicOperations() (map[ids.ID]*atomic.Requests, error) {
+	if len(tx.RewardUTXOBytes) == 0 {
+		return nil, nil
+	}
+
+	return map[ids.ID]*atomic.Requests{
+		tx.RewardChainID: {
+			PutRequests: []*atomic.Element{
+				{
+					Key:    tx.RewardUTXOID[:],
+					Value:  tx.RewardUTXOBytes,
+					Traits: tx.RewardOwnerTraits,
+				},
+			},
+		},
+	}, nil
+}
@@ -0,0 +1,24 @@
+// Copyright (C) 2019-2021, Ava Labs, Inc. All rights reserved.
+// See the file LICENSE for licensing terms.
+
+package txs
+
+import (
+	"github.com/ava-labs/avalanchego/ids"
+	"github.com/ava-labs/avalanchego/snow"
+)
+
+// UnsignedTx is the subset of a P-chain transaction's unsigned body that the
+// block manager interacts with directly, independent of the tx's concrete
+// type.
+type UnsignedTx interface {
+	ID() ids.ID
+	InitCtx(ctx *snow.Context)
+}
+
+// Tx is a signed P-chain transaction.
+type Tx struct {
+	Unsigned UnsignedTx
+}
+
+func (tx *Tx) ID() ids.ID { return tx.Unsigned.ID() }